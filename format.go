@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// BoardFormat decodes and encodes a batch of Sudoku boards in some textual
+// representation, so the solver isn't tied to a single puzzle layout and can
+// be dropped into a larger pipeline as a library component.
+type BoardFormat interface {
+	Decode(r io.Reader) ([]Board, error)
+	Encode(w io.Writer, boards []Board) error
+}
+
+// LineFormat is the original one-line-per-puzzle layout: 81 characters per
+// line, digits 1-9 for clues and '0' for a blank cell ('.' is also accepted
+// on decode for convenience).
+type LineFormat struct{}
+
+func (LineFormat) Decode(r io.Reader) ([]Board, error) { return decodeLines(r) }
+
+func (LineFormat) Encode(w io.Writer, boards []Board) error { return encodeLines(w, boards, '0') }
+
+// DottedFormat is the same one-line-per-puzzle layout as LineFormat but
+// renders blank cells as '.' instead of '0' on encode (both are accepted on
+// decode, same as LineFormat).
+type DottedFormat struct{}
+
+func (DottedFormat) Decode(r io.Reader) ([]Board, error) { return decodeLines(r) }
+
+func (DottedFormat) Encode(w io.Writer, boards []Board) error { return encodeLines(w, boards, '.') }
+
+// decodeLines implements the one-line-per-puzzle parsing shared by
+// LineFormat and DottedFormat; both '0' and '.' are accepted as blank.
+func decodeLines(r io.Reader) ([]Board, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading boards: %w", err)
+	}
+
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	boards := make([]Board, 0, len(lines))
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		if len(line) != boardSize {
+			logger.Warn("Skipping line: incorrect length",
+				"line_number", i+1,
+				"expected_length", boardSize,
+				"actual_length", len(line),
+			)
+			continue
+		}
+
+		var cells [boardSize]int
+		validLine := true
+		for j, char := range line {
+			switch {
+			case char >= '1' && char <= '9':
+				cells[j] = int(char - '0')
+			case char == '0' || char == '.':
+				cells[j] = 0
+			default:
+				logger.Warn("Skipping line: invalid character",
+					"line_number", i+1,
+					"position", j,
+					"character", string(char),
+				)
+				validLine = false
+			}
+			if !validLine {
+				break
+			}
+		}
+
+		if validLine {
+			boards = append(boards, Board{Cells: cells})
+		}
+	}
+
+	// Check if boards is empty only if there were non-empty lines processed
+	if len(boards) == 0 {
+		nonEmptyLines := false
+		for _, line := range lines {
+			if strings.TrimSpace(line) != "" {
+				nonEmptyLines = true
+				break
+			}
+		}
+		if nonEmptyLines {
+			return nil, fmt.Errorf("no valid boards found")
+		}
+	}
+
+	return boards, nil
+}
+
+// encodeLines writes boards in the one-line-per-puzzle layout, rendering
+// blank cells as blankChar.
+func encodeLines(w io.Writer, boards []Board, blankChar byte) error {
+	bw := bufio.NewWriter(w)
+	for _, b := range boards {
+		line := make([]byte, boardSize)
+		for i, v := range b.Cells {
+			if v == 0 {
+				line[i] = blankChar
+			} else {
+				line[i] = byte('0' + v)
+			}
+		}
+		if _, err := bw.Write(line); err != nil {
+			return err
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// SadManFormat reads and writes the SadMan Software Sudoku ".sdk" format:
+// one puzzle per 9 lines of 9 characters (digits for clues, '.' for blank),
+// with '#'-prefixed comments and blank lines ignored and puzzles separated
+// by a blank line on encode.
+type SadManFormat struct{}
+
+func (SadManFormat) Decode(r io.Reader) ([]Board, error) {
+	scanner := bufio.NewScanner(r)
+	var dataLines []string
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		dataLines = append(dataLines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading .sdk boards: %w", err)
+	}
+
+	if len(dataLines)%rowLength != 0 {
+		return nil, fmt.Errorf(".sdk input has %d data lines, not a multiple of %d", len(dataLines), rowLength)
+	}
+
+	boards := make([]Board, 0, len(dataLines)/rowLength)
+	for start := 0; start < len(dataLines); start += rowLength {
+		var cells [boardSize]int
+		for r := 0; r < rowLength; r++ {
+			line := dataLines[start+r]
+			if len(line) != colLength {
+				return nil, fmt.Errorf(".sdk row %d has length %d, expected %d", start+r+1, len(line), colLength)
+			}
+			for c, char := range line {
+				switch {
+				case char >= '1' && char <= '9':
+					cells[r*rowLength+c] = int(char - '0')
+				case char == '.' || char == '0':
+					cells[r*rowLength+c] = 0
+				default:
+					return nil, fmt.Errorf(".sdk row %d: invalid character %q", start+r+1, char)
+				}
+			}
+		}
+		boards = append(boards, Board{Cells: cells})
+	}
+	return boards, nil
+}
+
+func (SadManFormat) Encode(w io.Writer, boards []Board) error {
+	bw := bufio.NewWriter(w)
+	for i, b := range boards {
+		if i > 0 {
+			if err := bw.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+		for r := 0; r < rowLength; r++ {
+			row := make([]byte, colLength)
+			for c := 0; c < colLength; c++ {
+				v := b.Cells[r*rowLength+c]
+				if v == 0 {
+					row[c] = '.'
+				} else {
+					row[c] = byte('0' + v)
+				}
+			}
+			if _, err := bw.Write(row); err != nil {
+				return err
+			}
+			if err := bw.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// JSONFormat reads and writes boards as a JSON array of 81-int arrays, e.g.
+// [[0,0,3,...], [5,0,0,...]], one sub-array of clues per board.
+type JSONFormat struct{}
+
+func (JSONFormat) Decode(r io.Reader) ([]Board, error) {
+	var raw [][]int
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decoding JSON boards: %w", err)
+	}
+
+	boards := make([]Board, 0, len(raw))
+	for i, cells := range raw {
+		if len(cells) != boardSize {
+			return nil, fmt.Errorf("board %d has %d cells, expected %d", i, len(cells), boardSize)
+		}
+		var board Board
+		for j, v := range cells {
+			if v < 0 || v > 9 {
+				return nil, fmt.Errorf("board %d cell %d: value %d out of range 0-9", i, j, v)
+			}
+			board.Cells[j] = v
+		}
+		boards = append(boards, board)
+	}
+	return boards, nil
+}
+
+func (JSONFormat) Encode(w io.Writer, boards []Board) error {
+	raw := make([][]int, len(boards))
+	for i, b := range boards {
+		cells := make([]int, boardSize)
+		copy(cells, b.Cells[:])
+		raw[i] = cells
+	}
+	return json.NewEncoder(w).Encode(raw)
+}
+
+// formatByName resolves an -informat/-outformat flag value to a BoardFormat.
+// An empty name infers the format from path's file extension.
+func formatByName(name, path string) (BoardFormat, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return formatForExt(filepath.Ext(path)), nil
+	case "line":
+		return LineFormat{}, nil
+	case "dotted":
+		return DottedFormat{}, nil
+	case "sdk":
+		return SadManFormat{}, nil
+	case "json":
+		return JSONFormat{}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (want line, dotted, sdk, or json)", name)
+	}
+}
+
+func formatForExt(ext string) BoardFormat {
+	switch strings.ToLower(ext) {
+	case ".json":
+		return JSONFormat{}
+	case ".sdk":
+		return SadManFormat{}
+	case ".dotted":
+		return DottedFormat{}
+	default:
+		return LineFormat{}
+	}
+}
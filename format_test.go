@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// TestFormatsRoundTrip checks that every BoardFormat's Encode output can be
+// read back by its own Decode into an equal set of boards.
+func TestFormatsRoundTrip(t *testing.T) {
+	boards := []Board{boardFromString(t, wikipediaPuzzle)}
+
+	formats := []struct {
+		name string
+		fmt  BoardFormat
+	}{
+		{"line", LineFormat{}},
+		{"dotted", DottedFormat{}},
+		{"sdk", SadManFormat{}},
+		{"json", JSONFormat{}},
+	}
+
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := f.fmt.Encode(&buf, boards); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			decoded, err := f.fmt.Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if len(decoded) != len(boards) {
+				t.Fatalf("got %d boards, want %d", len(decoded), len(boards))
+			}
+			if decoded[0].Cells != boards[0].Cells {
+				t.Fatalf("round trip mismatch:\ngot:  %v\nwant: %v", decoded[0].Cells, boards[0].Cells)
+			}
+		})
+	}
+}
+
+// TestJSONFormatDecodeRejectsOutOfRangeCells checks that a cell value
+// outside 0-9 is rejected with an error rather than reaching the solver.
+func TestJSONFormatDecodeRejectsOutOfRangeCells(t *testing.T) {
+	cells := make([]int, boardSize)
+	cells[5] = -1
+
+	data, err := json.Marshal([][]int{cells})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	if _, err := (JSONFormat{}).Decode(bytes.NewReader(data)); err == nil {
+		t.Fatal("expected an error for an out-of-range cell value, got nil")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Generate produces a puzzle with a random full solution reduced to a
+// minimal set of clues, seeded for reproducibility: the same (difficulty,
+// seed) pair yields the same puzzle as long as no uniqueness check hits
+// checkTimeout (a timed-out check keeps the clue). difficulty is the clue floor —
+// removal stops once the puzzle has that many clues left, even if further
+// removals would still be unique. checkTimeout bounds each removal's
+// uniqueness check (0 disables it), same as the solver's per-board -timeout.
+func Generate(difficulty int, seed int64, checkTimeout time.Duration) Board {
+	rng := rand.New(rand.NewSource(seed))
+	solution := randomSolution(rng)
+	return reduceToMinimal(solution, difficulty, rng, checkTimeout)
+}
+
+// randomSolution fills an empty grid into a complete, valid Sudoku solution
+// by running the backtracking solver with digits tried in a randomized order
+// at each cell, so different seeds produce different solutions.
+func randomSolution(rng *rand.Rand) Board {
+	var board Board
+	state := NewSolverState(&board)
+	solveBacktrackRandom(context.Background(), state, 0, rng)
+
+	board.Cells = board.Guesses
+	board.Guesses = [boardSize]int{}
+	return board
+}
+
+// solveBacktrackRandom is SolveBacktrack's plain left-to-right search with
+// the digit order at each cell shuffled by rng, used to produce a random
+// full solution rather than always the lexicographically-first one.
+func solveBacktrackRandom(ctx context.Context, state *SolverState, startIdx int, rng *rand.Rand) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var emptyIdx = -1
+	for i := startIdx; i < boardSize; i++ {
+		if state.Board.Cells[i] == 0 && state.Board.Guesses[i] == 0 {
+			emptyIdx = i
+			break
+		}
+	}
+
+	if emptyIdx == -1 {
+		return true // Solved
+	}
+
+	row := emptyIdx / rowLength
+	col := emptyIdx % colLength
+	squareIdx := (row/3)*3 + (col / 3)
+
+	for _, n := range rng.Perm(9) {
+		num := n + 1
+		bit := uint16(1 << num)
+
+		if (state.RowsUsed[row]&bit) == 0 &&
+			(state.ColsUsed[col]&bit) == 0 &&
+			(state.SquaresUsed[squareIdx]&bit) == 0 {
+
+			state.assign(emptyIdx, num, row, col, squareIdx)
+
+			if solveBacktrackRandom(ctx, state, emptyIdx+1, rng) {
+				return true
+			}
+
+			state.Board.Guesses[emptyIdx] = 0
+			state.RowsUsed[row] &= ^bit
+			state.ColsUsed[col] &= ^bit
+			state.SquaresUsed[squareIdx] &= ^bit
+		}
+	}
+	return false
+}
+
+// reduceToMinimal removes clues from solution in random order, keeping each
+// removal only if the puzzle still has exactly one solution, until no
+// further removal preserves uniqueness or the clue count reaches floor.
+func reduceToMinimal(solution Board, floor int, rng *rand.Rand, checkTimeout time.Duration) Board {
+	puzzle := solution
+	clues := boardSize
+
+	for _, idx := range rng.Perm(boardSize) {
+		if clues <= floor {
+			break
+		}
+
+		removed := puzzle.Cells[idx]
+		if removed == 0 {
+			continue
+		}
+		puzzle.Cells[idx] = 0
+
+		state := NewSolverState(&puzzle)
+		if isUniqueWithinTimeout(state, checkTimeout) {
+			clues--
+		} else {
+			puzzle.Cells[idx] = removed
+		}
+	}
+
+	return puzzle
+}
+
+// isUniqueWithinTimeout reports whether state's board has exactly one
+// solution, bounding the search with timeout (if positive) the same way
+// Solver.checkUnique does, so a near-minimal puzzle that forces an expensive
+// full search can't stall generation indefinitely. A check that times out
+// is treated as not unique, leaving the clue in place.
+func isUniqueWithinTimeout(state *SolverState, timeout time.Duration) bool {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	count, _ := CountSolutions(ctx, state, 2)
+	return ctx.Err() == nil && count == 1
+}
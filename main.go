@@ -2,12 +2,11 @@ package main
 
 import (
 	"context"
-	"encoding/csv"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
-	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync"
@@ -16,9 +15,24 @@ import (
 
 // Command Line Flags
 var (
-	logLevel  = flag.String("loglevel", "info", "Set log level: debug, info, warn, error")
-	inputFile = flag.String("file", "./data/sudoku.many.17clue.txt", "Path to the Sudoku input file")
-	logDir    = flag.String("logdir", "./logs", "Directory for CSV log files")
+	logLevel    = flag.String("loglevel", "info", "Set log level: debug, info, warn, error")
+	inputFile   = flag.String("file", "./data/sudoku.many.17clue.txt", "Path to the Sudoku input file")
+	logDir      = flag.String("logdir", "./logs", "Directory for CSV log files")
+	workers     = flag.Int("workers", runtime.NumCPU(), "Number of concurrent solver workers (1 = sequential)")
+	timeout     = flag.Duration("timeout", 10*time.Second, "Per-board solve timeout (0 disables it)")
+	propagate   = flag.Bool("propagate", true, "Apply naked/hidden-single constraint propagation and MRV branching before backtracking")
+	unique      = flag.Bool("unique", false, "Check solution uniqueness instead of stopping at the first solution")
+	uniqueLimit = flag.Int("unique-limit", 2, "Max solutions to search for per board in -unique mode (values below 2 are raised to 2)")
+	generate    = flag.Int("generate", 0, "Generate N puzzles instead of solving -file, printing them to stdout")
+	difficulty  = flag.Int("difficulty", 25, "Minimum clue count to stop removing clues at when generating")
+	seed        = flag.Int64("seed", time.Now().UnixNano(), "Seed for the -generate puzzle RNG")
+
+	inFormat  = flag.String("informat", "", "Input puzzle format: line, dotted, sdk, or json (default: inferred from -file's extension)")
+	outFormat = flag.String("outformat", "", "Output puzzle format for -output (default: inferred from -output's extension)")
+	output    = flag.String("output", "", "Write solved boards to this path using -outformat (disabled if empty)")
+
+	csvDelim  = flag.String("csv-delim", ",", "Delimiter for -csv-format=csv/tsv (single character)")
+	csvFormat = flag.String("csv-format", "csv", "Per-board log format: csv, tsv, or jsonl")
 )
 
 var logger *slog.Logger
@@ -38,60 +52,6 @@ type Guess struct {
 	Row, Col, Value int
 }
 
-// SolverState holds the board and the cached used numbers for rows, cols, squares.
-type SolverState struct {
-	Board *Board
-
-	RowsUsed    [rowLength]uint16
-	ColsUsed    [colLength]uint16
-	SquaresUsed [(rowLength / 3) * (colLength / 3)]uint16
-}
-
-// NewSolverState initializes the state including pre-populating used numbers from clues.
-func NewSolverState(board *Board) *SolverState {
-	// Arrays are zero-initialized, which is correct for empty masks
-	state := &SolverState{Board: board}
-
-	// Pre-populate based on initial clues
-	for r := range 9 {
-		for c := range 9 {
-			idx := r*rowLength + c
-			num := board.Cells[idx]
-			if num != 0 {
-				squareIdx := (r/3)*3 + (c / 3)
-				bit := uint16(1 << num) // The bit corresponding to the number
-
-				// Check for initial board validity using bitmasks
-				if (state.RowsUsed[r]&bit) != 0 || (state.ColsUsed[c]&bit) != 0 || (state.SquaresUsed[squareIdx]&bit) != 0 {
-					logger.Warn("Initial board invalid: duplicate number found",
-						"number", num,
-						"row", r,
-						"col", c,
-					)
-				}
-				// Set the corresponding bit in the masks
-				state.RowsUsed[r] |= bit
-				state.ColsUsed[c] |= bit
-				state.SquaresUsed[squareIdx] |= bit
-			}
-		}
-	}
-	return state
-}
-
-// IsValid checks the pre-computed state if a guess is valid.
-func (ss *SolverState) IsValid(g Guess) bool {
-	if g.Value < 1 || g.Value > 9 {
-		return false
-	}
-	squareIdx := (g.Row/3)*3 + (g.Col / 3)
-	bit := uint16(1 << g.Value)
-	// Check if the bit is NOT set in any of the masks
-	return (ss.RowsUsed[g.Row]&bit) == 0 &&
-		(ss.ColsUsed[g.Col]&bit) == 0 &&
-		(ss.SquaresUsed[squareIdx]&bit) == 0
-}
-
 // Pretty displays the board, showing initial clues and guesses.
 func (b *Board) Pretty() string {
 	var sb strings.Builder
@@ -125,163 +85,39 @@ func (b *Board) Pretty() string {
 	return sb.String()
 }
 
-// Deserialize reads Sudoku puzzles from a file (one per line).
-func Deserialize(path string) ([]Board, error) {
-	bytes, err := os.ReadFile(path)
-	if err != nil {
-		// Return error to be logged by caller
-		return nil, fmt.Errorf("reading file %s: %w", path, err)
-	}
-
-	content := strings.ReplaceAll(string(bytes), "\r\n", "\n")
-	lines := strings.Split(content, "\n")
-	boards := make([]Board, 0, len(lines))
-
-	for i, line := range lines {
-		line = strings.TrimSpace(line)
-		if len(line) == 0 {
-			continue
-		}
-
-		if len(line) != boardSize {
-			logger.Warn("Skipping line: incorrect length",
-				"line_number", i+1,
-				"expected_length", boardSize,
-				"actual_length", len(line),
-			)
-			continue
-		}
-
-		var cells [boardSize]int
-		validLine := true
-		for j, char := range line {
-			if char >= '1' && char <= '9' {
-				cells[j] = int(char - '0')
-			} else if char == '0' || char == '.' {
-				cells[j] = 0
-			} else {
-				logger.Warn("Skipping line: invalid character",
-					"line_number", i+1,
-					"position", j,
-					"character", string(char),
-				)
-				validLine = false
-				break
-			}
-		}
-
-		if validLine {
-			boards = append(boards, Board{Cells: cells})
-		}
-	}
-	// Check if boards is empty only if there were non-empty lines processed
-	if len(boards) == 0 {
-		nonEmptyLines := false
-		for _, line := range lines {
-			if strings.TrimSpace(line) != "" {
-				nonEmptyLines = true
-				break
-			}
-		}
-		if nonEmptyLines {
-			return nil, fmt.Errorf("no valid boards found in file %s", path)
-		}
+// Serialize renders the puzzle's clues (not guesses) in the same one-line,
+// 81-character format that LineFormat reads, so generated puzzles can be
+// round-tripped straight back into the solver.
+func (b *Board) Serialize() string {
+	var sb strings.Builder
+	for _, v := range b.Cells {
+		sb.WriteByte(byte('0' + v))
 	}
-
-	return boards, nil
+	return sb.String()
 }
 
-func SolveBacktrack(state *SolverState, startIdx int) bool {
-	var emptyIdx = -1
-	for i := startIdx; i < boardSize; i++ {
-		if state.Board.Cells[i] == 0 && state.Board.Guesses[i] == 0 {
-			emptyIdx = i
-			break
-		}
-	}
-
-	if emptyIdx == -1 {
-		return true // Solved
-	}
-
-	row := emptyIdx / rowLength
-	col := emptyIdx % colLength
-	squareIdx := (row/3)*3 + (col / 3)
-
-	for num := 1; num <= 9; num++ {
-		bit := uint16(1 << num) // Calculate bit for the current number
-
-		// If result is 0, the bit is not set
-		if (state.RowsUsed[row]&bit) == 0 &&
-			(state.ColsUsed[col]&bit) == 0 &&
-			(state.SquaresUsed[squareIdx]&bit) == 0 {
-
-			state.Board.Guesses[emptyIdx] = num // Update boards guess
-
-			// Update masks using bitwise OR to set the bit
-			state.RowsUsed[row] |= bit
-			state.ColsUsed[col] |= bit
-			state.SquaresUsed[squareIdx] |= bit
-
-			if SolveBacktrack(state, emptyIdx+1) {
-				return true
-			}
-
-			state.Board.Guesses[emptyIdx] = 0 // Clear boards guess
-
-			// Revert masks using bitwise AND NOT which clears the bit:
-			state.RowsUsed[row] &= ^bit
-			state.ColsUsed[col] &= ^bit
-			state.SquaresUsed[squareIdx] &= ^bit
+// Solution returns a copy of b with every blank Cells entry filled in from
+// the matching Guesses entry, i.e. the completed board -output writes out.
+func (b Board) Solution() Board {
+	out := b
+	for i, v := range out.Cells {
+		if v == 0 {
+			out.Cells[i] = out.Guesses[i]
 		}
 	}
-	return false // Backtrack
-}
-
-// setupCSVLogger creates the log directory, generates a filename, opens the file,
-// creates a csv.Writer, and writes the header. (No changes needed)
-func setupCSVLogger(logDir string) (*csv.Writer, *os.File, error) {
-	err := os.MkdirAll(logDir, 0755)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create log directory '%s': %w", logDir, err)
-	}
-	timestamp := time.Now().Format("20060102_150405")
-	filename := filepath.Join(logDir, fmt.Sprintf("sudoku_log_%s.csv", timestamp))
-	file, err := os.Create(filename)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create CSV log file '%s': %w", filename, err)
-	}
-	logger.Info("CSV log file created", "path", filename)
-	writer := csv.NewWriter(file)
-	header := []string{"BoardIndex", "Status", "StartTime", "EndTime", "DurationSeconds"}
-	if err := writer.Write(header); err != nil {
-		file.Close()
-		return nil, nil, fmt.Errorf("failed to write CSV header to '%s': %w", filename, err)
-	}
-	writer.Flush()
-	if err := writer.Error(); err != nil {
-		file.Close()
-		return nil, nil, fmt.Errorf("error flushing CSV header to '%s': %w", filename, err)
-	}
-	return writer, file, nil
+	out.Guesses = [boardSize]int{}
+	return out
 }
 
-// csvLoggerGoroutine reads rows from a channel and writes them to the CSV file immediately.
-func csvLoggerGoroutine(csvWriter *csv.Writer, logChan <-chan []string, wg *sync.WaitGroup) {
-	defer wg.Done()
-
-	logger.Debug("CSV logger goroutine started")
-	for row := range logChan {
-		if err := csvWriter.Write(row); err != nil {
-			logger.Error("CSV goroutine: failed to write row", "error", err, "row_data", strings.Join(row, ","))
-		}
-		// Flush after every write to ensure data is written immediately
-		csvWriter.Flush()
-		if err := csvWriter.Error(); err != nil {
-			logger.Error("CSV goroutine: failed to flush writer", "error", err)
-		}
+// runGenerate prints count freshly generated puzzles to stdout, one per
+// line in LineFormat, seeded from seed so a run is reproducible. checkTimeout
+// bounds each clue-removal uniqueness check, same as the solver's -timeout.
+func runGenerate(count, difficulty int, seed int64, checkTimeout time.Duration) {
+	logger.Info("Generating puzzles", "count", count, "difficulty", difficulty, "seed", seed)
+	for i := 0; i < count; i++ {
+		board := Generate(difficulty, seed+int64(i), checkTimeout)
+		fmt.Println(board.Serialize())
 	}
-	logger.Debug("CSV logger goroutine finished")
 }
 
 func main() {
@@ -302,38 +138,53 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Unknown log level '%s', defaulting to INFO\n", *logLevel)
 	}
 	handlerOpts := slog.HandlerOptions{Level: programLevel}
-	handler := slog.NewTextHandler(os.Stdout, &handlerOpts)
+	handler := slog.NewTextHandler(os.Stderr, &handlerOpts)
 	logger = slog.New(handler)
 
-	// CSV Logger Setup
-	csvWriter, csvFile, err := setupCSVLogger(*logDir)
+	if *generate > 0 {
+		runGenerate(*generate, *difficulty, *seed, *timeout)
+		return
+	}
+
+	delim := ','
+	if *csvFormat == "tsv" {
+		delim = '\t'
+	}
+	if *csvDelim != "," {
+		if r := []rune(*csvDelim); len(r) > 0 {
+			delim = r[0]
+		}
+	}
+
+	// Result Logger Setup
+	resultLog, logFile, err := setupResultLogger(*logDir, *csvFormat, delim)
 	if err != nil {
-		logger.Error("Failed to set up CSV logger", "error", err)
+		logger.Error("Failed to set up result logger", "error", err)
 		os.Exit(1)
 	}
 
-	// Channel and WaitGroup for CSV Goroutine
-	csvLogChan := make(chan []string, 100)
-	var csvWg sync.WaitGroup
+	// Channel and WaitGroup for the logger Goroutine
+	logChan := make(chan []string, 100)
+	var logWg sync.WaitGroup
 
 	// Start the logger goroutine
-	csvWg.Add(1)
-	go csvLoggerGoroutine(csvWriter, csvLogChan, &csvWg)
+	logWg.Add(1)
+	go resultLoggerGoroutine(resultLog, logChan, &logWg)
 
 	// Ensure file is closed and goroutine finishes properly on exit
 	defer func() {
-		logger.Debug("Main: Closing CSV log channel")
-		close(csvLogChan) // Signal the logger goroutine to exit after processing remaining items
+		logger.Debug("Main: Closing result log channel")
+		close(logChan) // Signal the logger goroutine to exit after processing remaining items
 
-		logger.Debug("Main: Waiting for CSV logger goroutine to finish")
-		csvWg.Wait() // Wait for the logger goroutine to complete
-		logger.Debug("Main: CSV logger goroutine finished")
+		logger.Debug("Main: Waiting for result logger goroutine to finish")
+		logWg.Wait() // Wait for the logger goroutine to complete
+		logger.Debug("Main: Result logger goroutine finished")
 
 		// Now it's safe to close the file
-		if csvFile != nil {
-			logger.Debug("Main: Closing CSV file")
-			if err := csvFile.Close(); err != nil {
-				logger.Error("Error closing CSV file on exit", "error", err)
+		if logFile != nil {
+			logger.Debug("Main: Closing result log file")
+			if err := logFile.Close(); err != nil {
+				logger.Error("Error closing result log file on exit", "error", err)
 			}
 		}
 	}()
@@ -341,7 +192,19 @@ func main() {
 	overallStartTime := time.Now()
 	logger.Info("Starting Sudoku solver", "log_level", programLevel.Level(), "file", *inputFile, "csv_log_dir", *logDir)
 
-	boards, err := Deserialize(*inputFile)
+	inputFormat, err := formatByName(*inFormat, *inputFile)
+	if err != nil {
+		logger.Error("Failed to resolve input format", "error", err)
+		os.Exit(1)
+	}
+
+	inFile, err := os.Open(*inputFile)
+	if err != nil {
+		logger.Error("Failed to open input file", "error", err)
+		os.Exit(1)
+	}
+	boards, err := inputFormat.Decode(inFile)
+	inFile.Close()
 	if err != nil {
 		logger.Error("Failed to deserialize boards", "error", err)
 		os.Exit(1)
@@ -354,54 +217,44 @@ func main() {
 
 	logger.Info("Deserialized boards", "count", len(boards))
 
-	solvedCount := 0
-	// Solving Loop
-	for i := range boards {
-		boardStartTime := time.Now()
-		currentBoard := &boards[i]
-
-		logger.Info("Attempting board", "index", i+1)
+	solver := NewSolver(*workers, *timeout, *propagate)
+	solver.Unique = *unique
+	solver.UniqueLimit = *uniqueLimit
+	logger.Info("Starting solver pool", "workers", solver.Workers, "timeout", solver.Timeout.String())
 
-		if logger.Enabled(context.Background(), slog.LevelDebug) {
-			logger.Debug("Printing initial board state")
-			fmt.Println("Initial Board:")
-			fmt.Println(currentBoard.Pretty())
+	solvedCount := 0
+	// Solving Loop: drain results as workers complete them, in whatever order they finish.
+	// With -workers=1 this order matches the input order, preserving the original sequential behavior.
+	for res := range solver.Run(context.Background(), boards) {
+		switch res.status {
+		case "Solved":
+			solvedCount++
+			logger.Info("Successfully solved board", "index", res.index+1, "duration", res.duration.String())
+		case "Timeout":
+			logger.Warn("Board timed out", "index", res.index+1, "duration", res.duration.String())
+		default:
+			logger.Warn("Failed to solve board", "index", res.index+1, "duration", res.duration.String())
+		}
+		if res.uniqueness != "" {
+			logger.Info("Uniqueness check result", "index", res.index+1, "unique", res.uniqueness)
 		}
 
-		initState := NewSolverState(currentBoard)
-		solved := SolveBacktrack(initState, 0)
-		boardEndTime := time.Now()
-		boardDuration := boardEndTime.Sub(boardStartTime)
-
-		status := "Failed"
-		if solved {
-			solvedCount++
-			status = "Solved"
-			logger.Info("Successfully solved board", "index", i+1, "duration", boardDuration.String())
-			if logger.Enabled(context.Background(), slog.LevelDebug) {
-				logger.Debug("Printing solved board state")
-				fmt.Println("Solved Board:")
-				fmt.Println(currentBoard.Pretty())
-			}
-		} else {
-			logger.Warn("Failed to solve board", "index", i+1, "duration", boardDuration.String())
-			if logger.Enabled(context.Background(), slog.LevelDebug) {
-				logger.Debug("Printing board state at failure")
-				fmt.Println("Board state at failure:")
-				fmt.Println(currentBoard.Pretty())
-			}
+		if logger.Enabled(context.Background(), slog.LevelDebug) {
+			logger.Debug("Printing board state", "index", res.index+1, "status", res.status)
+			fmt.Println(boards[res.index].Pretty())
 		}
 
-		// Send data to CSV logger goroutine
-		csvRow := []string{
-			strconv.Itoa(i + 1),
-			status,
-			boardStartTime.Format(time.RFC3339),
-			boardEndTime.Format(time.RFC3339),
-			fmt.Sprintf("%.6f", boardDuration.Seconds()),
+		// Send data to the result logger goroutine
+		row := []string{
+			strconv.Itoa(res.index + 1),
+			res.status,
+			res.startTime.Format(time.RFC3339),
+			res.endTime.Format(time.RFC3339),
+			fmt.Sprintf("%.6f", res.duration.Seconds()),
+			strconv.Itoa(res.solvedCells),
+			res.uniqueness,
 		}
-		// Send the row to the channel
-		csvLogChan <- csvRow
+		logChan <- row
 	}
 
 	overallTime := time.Since(overallStartTime)
@@ -410,4 +263,37 @@ func main() {
 		"total_boards", len(boards),
 		"total_duration", overallTime.String(),
 	)
+
+	if *output != "" {
+		if err := writeOutput(*output, boards); err != nil {
+			logger.Error("Failed to write solved boards", "error", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// writeOutput encodes the solved boards to path using -outformat (or a
+// format inferred from path's extension).
+func writeOutput(path string, boards []Board) error {
+	outputFormat, err := formatByName(*outFormat, path)
+	if err != nil {
+		return fmt.Errorf("resolving output format: %w", err)
+	}
+
+	solved := make([]Board, len(boards))
+	for i, b := range boards {
+		solved[i] = b.Solution()
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating output file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if err := outputFormat.Encode(file, solved); err != nil {
+		return fmt.Errorf("encoding output to %s: %w", path, err)
+	}
+	logger.Info("Wrote solved boards", "path", path, "count", len(solved))
+	return nil
 }
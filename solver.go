@@ -0,0 +1,615 @@
+package main
+
+import (
+	"context"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// SolverState holds the board and the cached used numbers for rows, cols, squares.
+type SolverState struct {
+	Board *Board
+
+	RowsUsed    [rowLength]uint16
+	ColsUsed    [colLength]uint16
+	SquaresUsed [(rowLength / 3) * (colLength / 3)]uint16
+}
+
+// NewSolverState initializes the state including pre-populating used numbers from clues.
+func NewSolverState(board *Board) *SolverState {
+	state := &SolverState{}
+	state.Reset(board)
+	return state
+}
+
+// Reset re-initializes the state in place for a new board, so a *SolverState
+// can be reused across jobs (e.g. pulled from a sync.Pool) without reallocating.
+func (ss *SolverState) Reset(board *Board) {
+	ss.Board = board
+	ss.RowsUsed = [rowLength]uint16{}
+	ss.ColsUsed = [colLength]uint16{}
+	ss.SquaresUsed = [(rowLength / 3) * (colLength / 3)]uint16{}
+	for i := range board.Guesses {
+		board.Guesses[i] = 0
+	}
+
+	// Pre-populate based on initial clues
+	for r := range 9 {
+		for c := range 9 {
+			idx := r*rowLength + c
+			num := board.Cells[idx]
+			if num != 0 {
+				squareIdx := (r/3)*3 + (c / 3)
+				bit := uint16(1 << num) // The bit corresponding to the number
+
+				// Check for initial board validity using bitmasks
+				if (ss.RowsUsed[r]&bit) != 0 || (ss.ColsUsed[c]&bit) != 0 || (ss.SquaresUsed[squareIdx]&bit) != 0 {
+					logger.Warn("Initial board invalid: duplicate number found",
+						"number", num,
+						"row", r,
+						"col", c,
+					)
+				}
+				// Set the corresponding bit in the masks
+				ss.RowsUsed[r] |= bit
+				ss.ColsUsed[c] |= bit
+				ss.SquaresUsed[squareIdx] |= bit
+			}
+		}
+	}
+}
+
+// IsValid checks the pre-computed state if a guess is valid.
+func (ss *SolverState) IsValid(g Guess) bool {
+	if g.Value < 1 || g.Value > 9 {
+		return false
+	}
+	squareIdx := (g.Row/3)*3 + (g.Col / 3)
+	bit := uint16(1 << g.Value)
+	// Check if the bit is NOT set in any of the masks
+	return (ss.RowsUsed[g.Row]&bit) == 0 &&
+		(ss.ColsUsed[g.Col]&bit) == 0 &&
+		(ss.SquaresUsed[squareIdx]&bit) == 0
+}
+
+// candidateMask is the set of bits a cell could ever hold: digits 1-9.
+const candidateMask = 0x03FE
+
+// SolveBacktrack attempts to fill state.Board starting from startIdx, mutating
+// Board.Guesses and the cached masks in place. ctx is checked at every
+// recursion entry so a per-board timeout can abort a run cleanly; on
+// cancellation it returns false with ctx.Err() set.
+//
+// When propagate is true, Propagate is run at every recursion level to fill
+// in forced cells before a branching cell is chosen, and the branching cell
+// is the one with the fewest remaining candidates (MRV) rather than the
+// left-to-right scan used when propagate is false. Only that scan starts at
+// startIdx; the propagate path considers every empty cell.
+func SolveBacktrack(ctx context.Context, state *SolverState, startIdx int, propagate bool) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if !propagate {
+		return solveBacktrackPlain(ctx, state, startIdx)
+	}
+
+	savedRows, savedCols, savedSquares := state.RowsUsed, state.ColsUsed, state.SquaresUsed
+	savedGuesses := state.Board.Guesses
+
+	if _, contradiction := Propagate(state); contradiction {
+		state.RowsUsed, state.ColsUsed, state.SquaresUsed = savedRows, savedCols, savedSquares
+		state.Board.Guesses = savedGuesses
+		return false
+	}
+
+	emptyIdx, candidates := state.mrvCell()
+	if emptyIdx == -1 {
+		return true // Solved
+	}
+	if candidates == 0 {
+		state.RowsUsed, state.ColsUsed, state.SquaresUsed = savedRows, savedCols, savedSquares
+		state.Board.Guesses = savedGuesses
+		return false
+	}
+
+	row := emptyIdx / rowLength
+	col := emptyIdx % colLength
+	squareIdx := (row/3)*3 + (col / 3)
+
+	for num := 1; num <= 9; num++ {
+		bit := uint16(1 << num)
+		if candidates&bit == 0 {
+			continue
+		}
+
+		state.assign(emptyIdx, num, row, col, squareIdx)
+
+		if SolveBacktrack(ctx, state, emptyIdx+1, propagate) {
+			return true
+		}
+
+		state.Board.Guesses[emptyIdx] = 0
+		state.RowsUsed[row] &= ^bit
+		state.ColsUsed[col] &= ^bit
+		state.SquaresUsed[squareIdx] &= ^bit
+	}
+
+	state.RowsUsed, state.ColsUsed, state.SquaresUsed = savedRows, savedCols, savedSquares
+	state.Board.Guesses = savedGuesses
+	return false // Backtrack
+}
+
+// solveBacktrackPlain is the original left-to-right backtracking search, kept
+// around for -propagate=false so it can be A/B benchmarked against the
+// propagation+MRV path above.
+func solveBacktrackPlain(ctx context.Context, state *SolverState, startIdx int) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var emptyIdx = -1
+	for i := startIdx; i < boardSize; i++ {
+		if state.Board.Cells[i] == 0 && state.Board.Guesses[i] == 0 {
+			emptyIdx = i
+			break
+		}
+	}
+
+	if emptyIdx == -1 {
+		return true // Solved
+	}
+
+	row := emptyIdx / rowLength
+	col := emptyIdx % colLength
+	squareIdx := (row/3)*3 + (col / 3)
+
+	for num := 1; num <= 9; num++ {
+		bit := uint16(1 << num) // Calculate bit for the current number
+
+		// If result is 0, the bit is not set
+		if (state.RowsUsed[row]&bit) == 0 &&
+			(state.ColsUsed[col]&bit) == 0 &&
+			(state.SquaresUsed[squareIdx]&bit) == 0 {
+
+			state.Board.Guesses[emptyIdx] = num // Update boards guess
+
+			// Update masks using bitwise OR to set the bit
+			state.RowsUsed[row] |= bit
+			state.ColsUsed[col] |= bit
+			state.SquaresUsed[squareIdx] |= bit
+
+			if solveBacktrackPlain(ctx, state, emptyIdx+1) {
+				return true
+			}
+
+			state.Board.Guesses[emptyIdx] = 0 // Clear boards guess
+
+			// Revert masks using bitwise AND NOT which clears the bit:
+			state.RowsUsed[row] &= ^bit
+			state.ColsUsed[col] &= ^bit
+			state.SquaresUsed[squareIdx] &= ^bit
+		}
+	}
+	return false // Backtrack
+}
+
+// CountSolutions counts up to limit distinct solutions reachable from
+// state's current board and returns that count along with a copy of the
+// first solution found (nil if none). A properly specified Sudoku has
+// exactly one solution, so limit=2 is enough to distinguish a unique puzzle
+// from one with multiple solutions without enumerating them all. ctx is
+// checked at each recursion entry, same as SolveBacktrack, so a hard puzzle
+// that forces a full search can still be bounded by a timeout.
+//
+// Like SolveBacktrack's propagate path, the search runs Propagate and
+// branches on the MRV cell at every level; propagation only ever forces
+// moves a plain search would eventually make anyway, so the solution count
+// is unaffected but hard, near-minimal puzzles are counted orders of
+// magnitude faster.
+func CountSolutions(ctx context.Context, state *SolverState, limit int) (count int, first *Board) {
+	countSolutions(ctx, state, 0, limit, &count, &first)
+	return count, first
+}
+
+func countSolutions(ctx context.Context, state *SolverState, startIdx int, limit int, count *int, first **Board) {
+	if ctx.Err() != nil {
+		return
+	}
+	if *count >= limit {
+		return
+	}
+
+	savedRows, savedCols, savedSquares := state.RowsUsed, state.ColsUsed, state.SquaresUsed
+	savedGuesses := state.Board.Guesses
+	restore := func() {
+		state.RowsUsed, state.ColsUsed, state.SquaresUsed = savedRows, savedCols, savedSquares
+		state.Board.Guesses = savedGuesses
+	}
+
+	if _, contradiction := Propagate(state); contradiction {
+		restore()
+		return
+	}
+
+	emptyIdx, candidates := state.mrvCell()
+	if emptyIdx == -1 {
+		*count++
+		if *first == nil {
+			solved := *state.Board
+			*first = &solved
+		}
+		restore()
+		return
+	}
+	if candidates == 0 {
+		restore()
+		return
+	}
+
+	row := emptyIdx / rowLength
+	col := emptyIdx % colLength
+	squareIdx := (row/3)*3 + (col / 3)
+
+	for num := 1; num <= 9 && *count < limit; num++ {
+		bit := uint16(1 << num)
+		if candidates&bit == 0 {
+			continue
+		}
+
+		state.assign(emptyIdx, num, row, col, squareIdx)
+		countSolutions(ctx, state, emptyIdx+1, limit, count, first)
+
+		state.Board.Guesses[emptyIdx] = 0
+		state.RowsUsed[row] &= ^bit
+		state.ColsUsed[col] &= ^bit
+		state.SquaresUsed[squareIdx] &= ^bit
+	}
+
+	restore()
+}
+
+// mrvCell scans for the empty cell with the fewest remaining candidates
+// (minimum remaining values heuristic), returning its index and candidate
+// mask. It returns (-1, 0) if the board has no empty cells.
+func (ss *SolverState) mrvCell() (idx int, candidates uint16) {
+	best, bestCount := -1, 10
+	var bestCandidates uint16
+	for i := 0; i < boardSize; i++ {
+		if ss.Board.Cells[i] != 0 || ss.Board.Guesses[i] != 0 {
+			continue
+		}
+		r, c := i/rowLength, i%colLength
+		sq := (r/3)*3 + (c / 3)
+		cand := uint16(candidateMask) &^ (ss.RowsUsed[r] | ss.ColsUsed[c] | ss.SquaresUsed[sq])
+		n := bits.OnesCount16(cand)
+		if n < bestCount {
+			best, bestCount, bestCandidates = i, n, cand
+			if n == 0 {
+				break
+			}
+		}
+	}
+	return best, bestCandidates
+}
+
+// assign places num at idx and updates the row/col/square masks to match.
+func (ss *SolverState) assign(idx, num, row, col, squareIdx int) {
+	bit := uint16(1 << num)
+	ss.Board.Guesses[idx] = num
+	ss.RowsUsed[row] |= bit
+	ss.ColsUsed[col] |= bit
+	ss.SquaresUsed[squareIdx] |= bit
+}
+
+// Propagate applies naked-single and hidden-single constraint propagation to
+// state until a fixpoint is reached, assigning any cell that is forced by the
+// current row/col/square masks. It reports whether any assignment was made
+// and whether the resulting state is a contradiction: an empty cell left
+// with zero candidates, or a unit missing a digit that no remaining cell in
+// that unit can hold.
+func Propagate(state *SolverState) (changed bool, contradiction bool) {
+	for {
+		progressed := false
+
+		for i := 0; i < boardSize; i++ {
+			if state.Board.Cells[i] != 0 || state.Board.Guesses[i] != 0 {
+				continue
+			}
+			r, c := i/rowLength, i%colLength
+			sq := (r/3)*3 + (c / 3)
+			candidates := uint16(candidateMask) &^ (state.RowsUsed[r] | state.ColsUsed[c] | state.SquaresUsed[sq])
+			switch bits.OnesCount16(candidates) {
+			case 0:
+				return changed, true
+			case 1:
+				num := bits.TrailingZeros16(candidates)
+				state.assign(i, num, r, c, sq)
+				changed, progressed = true, true
+			}
+		}
+
+		if found, contra := state.propagateHiddenSingles(); contra {
+			return changed, true
+		} else if found {
+			changed, progressed = true, true
+		}
+
+		if !progressed {
+			break
+		}
+	}
+	return changed, false
+}
+
+// propagateHiddenSingles finds, for every row/column/square, any digit that
+// exactly one of the unit's empty cells can still hold and assigns it there.
+func (ss *SolverState) propagateHiddenSingles() (found bool, contradiction bool) {
+	for unit := 0; unit < 9; unit++ {
+		for _, cells := range [][9]int{rowCells(unit), colCells(unit), squareCells(unit)} {
+			f, contra := ss.hiddenSingleInUnit(cells)
+			if contra {
+				return found, true
+			}
+			found = found || f
+		}
+	}
+	return found, false
+}
+
+// hiddenSingleInUnit checks every digit against the empty cells of a single
+// unit (one row, column, or square), assigning any digit that only one cell
+// in the unit still admits.
+func (ss *SolverState) hiddenSingleInUnit(cells [9]int) (found bool, contradiction bool) {
+	for num := 1; num <= 9; num++ {
+		bit := uint16(1 << num)
+		count, place := 0, -1
+		for _, idx := range cells {
+			if ss.Board.Cells[idx] != 0 || ss.Board.Guesses[idx] != 0 {
+				continue
+			}
+			r, c := idx/rowLength, idx%colLength
+			sq := (r/3)*3 + (c / 3)
+			candidates := uint16(candidateMask) &^ (ss.RowsUsed[r] | ss.ColsUsed[c] | ss.SquaresUsed[sq])
+			if candidates&bit != 0 {
+				count++
+				place = idx
+			}
+		}
+		switch count {
+		case 0:
+			// The digit is already placed elsewhere in the unit, or genuinely
+			// has nowhere left to go; only the latter is a contradiction.
+			digitPlaced := false
+			for _, idx := range cells {
+				if ss.Board.Cells[idx] == num || ss.Board.Guesses[idx] == num {
+					digitPlaced = true
+					break
+				}
+			}
+			if !digitPlaced {
+				return found, true
+			}
+		case 1:
+			r, c := place/rowLength, place%colLength
+			sq := (r/3)*3 + (c / 3)
+			ss.assign(place, num, r, c, sq)
+			found = true
+		}
+	}
+	return found, false
+}
+
+// rowCells returns the 9 board indices making up row r.
+func rowCells(r int) [9]int {
+	var cells [9]int
+	for c := range 9 {
+		cells[c] = r*rowLength + c
+	}
+	return cells
+}
+
+// colCells returns the 9 board indices making up column c.
+func colCells(c int) [9]int {
+	var cells [9]int
+	for r := range 9 {
+		cells[r] = r*rowLength + c
+	}
+	return cells
+}
+
+// squareCells returns the 9 board indices making up 3x3 square sq (0-8, left
+// to right, top to bottom).
+func squareCells(sq int) [9]int {
+	var cells [9]int
+	baseRow := (sq / 3) * 3
+	baseCol := (sq % 3) * 3
+	i := 0
+	for dr := range 3 {
+		for dc := range 3 {
+			cells[i] = (baseRow+dr)*rowLength + baseCol + dc
+			i++
+		}
+	}
+	return cells
+}
+
+// countFilled returns the number of cells in the board that carry either an
+// initial clue or a solver guess, reported as the SolvedCells log column. A
+// search that fails or times out undoes its guesses as it unwinds, so for
+// such a board this is the clue count.
+func countFilled(board *Board) int {
+	count := 0
+	for i := range board.Cells {
+		if board.Cells[i] != 0 || board.Guesses[i] != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// solverStatePool reuses *SolverState values across jobs so the worker pool
+// doesn't reallocate the mask arrays for every board.
+var solverStatePool = sync.Pool{
+	New: func() any {
+		return &SolverState{}
+	},
+}
+
+// job is a unit of work handed to a solver worker.
+type job struct {
+	index int
+	board *Board
+}
+
+// result is what a solver worker reports back for a single board.
+type result struct {
+	index       int
+	status      string
+	uniqueness  string // "Unique", "MultipleSolutions", or "Unsolvable" when Solver.Unique is set; "" on timeout or otherwise
+	startTime   time.Time
+	endTime     time.Time
+	duration    time.Duration
+	solvedCells int
+}
+
+// Solver runs boards through the backtracking solver using a pool of workers.
+type Solver struct {
+	Workers   int
+	Timeout   time.Duration
+	Propagate bool
+
+	// Unique switches workers from solve-first-solution to the uniqueness
+	// check driven by CountSolutions, up to UniqueLimit solutions per board.
+	Unique      bool
+	UniqueLimit int
+}
+
+// NewSolver builds a Solver with the given worker count, per-board timeout,
+// and whether to run constraint propagation + MRV branching. A non-positive
+// workers value is treated as 1 (sequential).
+func NewSolver(workers int, timeout time.Duration, propagate bool) *Solver {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Solver{Workers: workers, Timeout: timeout, Propagate: propagate}
+}
+
+// Run solves boards concurrently across s.Workers goroutines and returns a
+// channel of results, one per board, in completion order. With Workers == 1
+// boards are solved in input order, matching the original sequential solver.
+func (s *Solver) Run(ctx context.Context, boards []Board) <-chan result {
+	jobs := make(chan job, len(boards))
+	results := make(chan result, len(boards))
+
+	var wg sync.WaitGroup
+	wg.Add(s.Workers)
+	for w := 0; w < s.Workers; w++ {
+		go func() {
+			defer wg.Done()
+			s.work(ctx, jobs, results)
+		}()
+	}
+
+	for i := range boards {
+		jobs <- job{index: i, board: &boards[i]}
+	}
+	close(jobs)
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// work pulls jobs off the channel until it's drained, solving each one with a
+// pooled *SolverState and a per-board timeout context.
+func (s *Solver) work(ctx context.Context, jobs <-chan job, results chan<- result) {
+	for j := range jobs {
+		results <- s.solveOne(ctx, j)
+	}
+}
+
+func (s *Solver) solveOne(parent context.Context, j job) result {
+	startTime := time.Now()
+
+	state := solverStatePool.Get().(*SolverState)
+	state.Reset(j.board)
+
+	var status, uniqueness string
+	if s.Unique {
+		status, uniqueness = s.checkUnique(parent, state, j.board)
+	} else {
+		status = s.solve(parent, state)
+	}
+
+	solverStatePool.Put(state)
+	endTime := time.Now()
+
+	return result{
+		index:       j.index,
+		status:      status,
+		uniqueness:  uniqueness,
+		startTime:   startTime,
+		endTime:     endTime,
+		duration:    endTime.Sub(startTime),
+		solvedCells: countFilled(j.board),
+	}
+}
+
+// solve runs the regular single-solution search under the configured
+// per-board timeout, returning "Solved", "Timeout", or "Failed".
+func (s *Solver) solve(parent context.Context, state *SolverState) string {
+	ctx := parent
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, s.Timeout)
+		defer cancel()
+	}
+
+	switch solved := SolveBacktrack(ctx, state, 0, s.Propagate); {
+	case solved:
+		return "Solved"
+	case ctx.Err() != nil:
+		return "Timeout"
+	default:
+		return "Failed"
+	}
+}
+
+// checkUnique runs the uniqueness check via CountSolutions under the same
+// configured per-board timeout as solve, writing the first solution found
+// back into board.Guesses for display/output, and returns the Status/Unique
+// column pair for the CSV log.
+func (s *Solver) checkUnique(parent context.Context, state *SolverState, board *Board) (status, uniqueness string) {
+	limit := s.UniqueLimit
+	if limit < 2 {
+		limit = 2
+	}
+
+	ctx := parent
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, s.Timeout)
+		defer cancel()
+	}
+
+	count, first := CountSolutions(ctx, state, limit)
+	if first != nil {
+		board.Guesses = first.Guesses
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		return "Timeout", ""
+	case count == 0:
+		return "Failed", "Unsolvable"
+	case count == 1:
+		return "Solved", "Unique"
+	default:
+		return "Solved", "MultipleSolutions"
+	}
+}
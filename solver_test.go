@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// boardFromString builds a Board's clues from an 81-character digit string
+// ('0' for blank), the same layout LineFormat reads.
+func boardFromString(t *testing.T, clues string) Board {
+	t.Helper()
+	if len(clues) != boardSize {
+		t.Fatalf("puzzle string has length %d, want %d", len(clues), boardSize)
+	}
+	var board Board
+	for i, ch := range clues {
+		board.Cells[i] = int(ch - '0')
+	}
+	return board
+}
+
+// bitsFor returns the uint16 mask with a bit set for every num.
+func bitsFor(nums ...int) uint16 {
+	var mask uint16
+	for _, n := range nums {
+		mask |= 1 << n
+	}
+	return mask
+}
+
+const wikipediaPuzzle = "530070000600195000098000060800060003400803001700020006060000280000419005000080079"
+
+// TestSolveBacktrackPropagateEquivalence checks that, on puzzles with a
+// unique solution, constraint propagation and MRV branching find the same
+// solution as the plain left-to-right search: propagation only ever forces
+// moves every solution shares, so with one solution the two paths must agree.
+func TestSolveBacktrackPropagateEquivalence(t *testing.T) {
+	puzzles := []Board{
+		boardFromString(t, wikipediaPuzzle),
+		Generate(30, 42, 0),
+	}
+
+	for i, puzzle := range puzzles {
+		plain := &Board{Cells: puzzle.Cells}
+		plainState := NewSolverState(plain)
+		if !SolveBacktrack(context.Background(), plainState, 0, false) {
+			t.Fatalf("puzzle %d: plain search failed to solve", i)
+		}
+
+		propagated := &Board{Cells: puzzle.Cells}
+		propState := NewSolverState(propagated)
+		if !SolveBacktrack(context.Background(), propState, 0, true) {
+			t.Fatalf("puzzle %d: propagate search failed to solve", i)
+		}
+
+		if plain.Guesses != propagated.Guesses {
+			t.Fatalf("puzzle %d: propagate and plain search disagree\nplain:     %v\npropagate: %v",
+				i, plain.Guesses, propagated.Guesses)
+		}
+	}
+}
+
+// TestHiddenSingleInUnitFindsForcedDigit sets up a row where digit 5 can
+// only go in one cell even though that cell also admits digit 2 (so the
+// naked-single check alone wouldn't resolve it), and checks that
+// hiddenSingleInUnit places it there.
+func TestHiddenSingleInUnitFindsForcedDigit(t *testing.T) {
+	board := &Board{}
+	board.Cells[2] = 1
+	board.Cells[3] = 3
+	board.Cells[4] = 4
+	board.Cells[5] = 6
+	board.Cells[6] = 7
+	board.Cells[7] = 8
+	board.Cells[8] = 9
+
+	state := &SolverState{Board: board}
+	state.RowsUsed[0] = bitsFor(1, 3, 4, 6, 7, 8, 9)
+	state.ColsUsed[0] = bitsFor(5) // col 0 can still take 2, but not 5
+
+	found, contradiction := state.hiddenSingleInUnit(rowCells(0))
+	if contradiction {
+		t.Fatal("unexpected contradiction")
+	}
+	if !found {
+		t.Fatal("expected a hidden single to be found")
+	}
+	if state.Board.Guesses[1] != 5 {
+		t.Fatalf("expected digit 5 placed at index 1, got Guesses[1]=%d", state.Board.Guesses[1])
+	}
+	if state.Board.Guesses[0] != 0 {
+		t.Fatalf("index 0 should be left unassigned, got Guesses[0]=%d", state.Board.Guesses[0])
+	}
+}
+
+// TestHiddenSingleInUnitDetectsContradiction sets up a row that's missing
+// digit 5 entirely, with every remaining cell also blocked from holding it,
+// and checks that hiddenSingleInUnit reports a contradiction.
+func TestHiddenSingleInUnitDetectsContradiction(t *testing.T) {
+	board := &Board{}
+	board.Cells[1] = 1
+	board.Cells[2] = 2
+	board.Cells[3] = 3
+	board.Cells[4] = 4
+	board.Cells[5] = 6
+	board.Cells[6] = 7
+	board.Cells[7] = 8
+	board.Cells[8] = 9
+
+	state := &SolverState{Board: board}
+	state.RowsUsed[0] = bitsFor(1, 2, 3, 4, 6, 7, 8, 9)
+	state.ColsUsed[0] = bitsFor(5) // the only empty cell can't hold 5 either
+
+	_, contradiction := state.hiddenSingleInUnit(rowCells(0))
+	if !contradiction {
+		t.Fatal("expected a contradiction: digit 5 has nowhere left to go in the row")
+	}
+}
+
+// TestCountSolutionsVerdicts checks the three counts Solver.checkUnique
+// distinguishes between: exactly one solution, more than one, and none.
+func TestCountSolutionsVerdicts(t *testing.T) {
+	t.Run("unique", func(t *testing.T) {
+		board := boardFromString(t, wikipediaPuzzle)
+		state := NewSolverState(&board)
+
+		count, first := CountSolutions(context.Background(), state, 2)
+		if count != 1 {
+			t.Fatalf("got count %d, want 1", count)
+		}
+		if first == nil {
+			t.Fatal("expected the solution to be returned")
+		}
+	})
+
+	t.Run("multiple", func(t *testing.T) {
+		var board Board // no clues at all: many solutions
+		state := NewSolverState(&board)
+
+		count, _ := CountSolutions(context.Background(), state, 2)
+		if count != 2 {
+			t.Fatalf("got count %d, want 2 (limit reached)", count)
+		}
+	})
+
+	t.Run("unsolvable", func(t *testing.T) {
+		// Solve the Wikipedia puzzle to get a valid complete grid, blank one
+		// cell, and also block its only remaining candidate so no digit fits.
+		board := boardFromString(t, wikipediaPuzzle)
+		state := NewSolverState(&board)
+		if !SolveBacktrack(context.Background(), state, 0, true) {
+			t.Fatal("failed to solve the puzzle used to build the fixture")
+		}
+
+		solved := board.Solution()
+		removedDigit := solved.Cells[0]
+		solved.Cells[0] = 0
+
+		contra := NewSolverState(&solved)
+		contra.ColsUsed[0] |= uint16(1 << removedDigit)
+
+		count, first := CountSolutions(context.Background(), contra, 2)
+		if count != 0 || first != nil {
+			t.Fatalf("got count=%d first=%v, want 0 and nil", count, first)
+		}
+	})
+}